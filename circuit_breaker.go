@@ -0,0 +1,100 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// breakerState is the state of a circuitBreaker.
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// circuitBreaker protects a single chat from a Client that keeps hammering
+// it once Telegram has made clear it isn't receiving messages right now.
+// It is a standard closed -> open -> half-open state machine: closed allows
+// every call and counts consecutive failures; once threshold is reached it
+// opens for cooldown; after cooldown elapses a single half-open probe is
+// let through, closing the breaker on success or reopening it on failure.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu            sync.Mutex
+	state         breakerState
+	fails         int
+	openUntil     time.Time
+	probeInFlight bool
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// allow reports whether a call may proceed, transitioning an open breaker
+// to half-open once cooldown has elapsed and admitting exactly one probe
+// call while half-open.
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if time.Now().Before(b.openUntil) {
+			return false
+		}
+		b.state = breakerHalfOpen
+		b.probeInFlight = true
+		return true
+	case breakerHalfOpen:
+		return !b.probeInFlight
+	default:
+		return true
+	}
+}
+
+// recordSuccess closes the breaker, clearing any accumulated failures.
+func (b *circuitBreaker) recordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = breakerClosed
+	b.fails = 0
+	b.probeInFlight = false
+}
+
+// recordFailure registers the outcome of a failed call. A half-open probe
+// failing reopens the breaker unconditionally, since there is only ever one
+// probe in flight. While closed, only countable failures (429/5xx/timeout)
+// accumulate toward threshold. retryAfter, when set, overrides cooldown as
+// the authoritative wait Telegram itself asked for.
+func (b *circuitBreaker) recordFailure(countable bool, retryAfter time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	cooldown := b.cooldown
+	if retryAfter > 0 {
+		cooldown = retryAfter
+	}
+
+	if b.state == breakerHalfOpen {
+		b.state = breakerOpen
+		b.openUntil = time.Now().Add(cooldown)
+		b.probeInFlight = false
+		return
+	}
+
+	if !countable {
+		return
+	}
+
+	b.fails++
+	if b.fails >= b.threshold {
+		b.state = breakerOpen
+		b.openUntil = time.Now().Add(cooldown)
+	}
+}
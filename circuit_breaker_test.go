@@ -0,0 +1,91 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreakerOpensAfterThreshold(t *testing.T) {
+	t.Parallel()
+
+	b := newCircuitBreaker(3, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		assert.True(t, b.allow())
+		b.recordFailure(true, 0)
+	}
+	assert.Equal(t, breakerClosed, b.state)
+
+	assert.True(t, b.allow())
+	b.recordFailure(true, 0)
+	assert.Equal(t, breakerOpen, b.state)
+
+	// The breaker is open, so further calls are short-circuited.
+	assert.False(t, b.allow())
+}
+
+func TestCircuitBreakerIgnoresNonCountableFailures(t *testing.T) {
+	t.Parallel()
+
+	b := newCircuitBreaker(1, time.Minute)
+
+	assert.True(t, b.allow())
+	b.recordFailure(false, 0)
+	assert.Equal(t, breakerClosed, b.state)
+	assert.True(t, b.allow())
+}
+
+func TestCircuitBreakerHalfOpenProbeSuccessCloses(t *testing.T) {
+	t.Parallel()
+
+	b := newCircuitBreaker(1, 10*time.Millisecond)
+
+	assert.True(t, b.allow())
+	b.recordFailure(true, 0)
+	assert.Equal(t, breakerOpen, b.state)
+
+	time.Sleep(20 * time.Millisecond)
+
+	// Cooldown elapsed: exactly one probe is let through.
+	assert.True(t, b.allow())
+	assert.False(t, b.allow())
+
+	b.recordSuccess()
+	assert.Equal(t, breakerClosed, b.state)
+	assert.True(t, b.allow())
+}
+
+func TestCircuitBreakerHalfOpenProbeFailureReopens(t *testing.T) {
+	t.Parallel()
+
+	b := newCircuitBreaker(1, 10*time.Millisecond)
+
+	assert.True(t, b.allow())
+	b.recordFailure(true, 0)
+	assert.Equal(t, breakerOpen, b.state)
+
+	time.Sleep(20 * time.Millisecond)
+
+	assert.True(t, b.allow())
+	b.recordFailure(true, 0)
+	assert.Equal(t, breakerOpen, b.state)
+	assert.False(t, b.allow())
+}
+
+func TestCircuitBreakerRetryAfterOverridesCooldown(t *testing.T) {
+	t.Parallel()
+
+	b := newCircuitBreaker(1, time.Minute)
+
+	assert.True(t, b.allow())
+	b.recordFailure(true, 10*time.Millisecond)
+	assert.Equal(t, breakerOpen, b.state)
+
+	time.Sleep(20 * time.Millisecond)
+
+	// retry_after (10ms) should have been honoured instead of the
+	// configured one-minute cooldown.
+	assert.True(t, b.allow())
+}
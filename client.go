@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// Client sends rendered log messages to the Telegram Bot API.
+type Client struct {
+	cfg        ClientConfig
+	httpClient *http.Client
+	breaker    *circuitBreaker
+
+	mu       sync.Mutex
+	tailID   int
+	tailText string
+	lastEdit time.Time
+}
+
+// NewClient builds a Client for the given configuration. It fails only if
+// cfg.ProxyURL can't be turned into a working transport; parseClientConfig
+// already validates the URL's scheme, so this is expected to succeed for
+// any ClientConfig produced by it.
+func NewClient(cfg ClientConfig) (*Client, error) {
+	transport, err := newProxyTransport(cfg.ProxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure proxy: %w", err)
+	}
+
+	return &Client{
+		cfg: cfg,
+		httpClient: &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: transport,
+		},
+		breaker: newCircuitBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown),
+	}, nil
+}
+
+// newProxyTransport builds the http.RoundTripper that routes requests
+// through rawProxyURL, or nil (the http.Client default) when it's empty.
+// http(s):// URLs use the transport's built-in CONNECT-based proxying;
+// socks5:// and socks5h:// dial through a SOCKS5 proxy instead, the only
+// difference being whether DNS resolution happens locally (socks5) or on
+// the proxy (socks5h).
+func newProxyTransport(rawProxyURL string) (http.RoundTripper, error) {
+	if rawProxyURL == "" {
+		return nil, nil
+	}
+
+	u, err := url.Parse(rawProxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse proxy URL: %w", err)
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		return &http.Transport{Proxy: http.ProxyURL(u)}, nil
+	case "socks5", "socks5h":
+		dialer, err := proxy.FromURL(u, proxy.Direct)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build SOCKS5 dialer: %w", err)
+		}
+		return &http.Transport{Dial: dialer.Dial}, nil
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+}
+
+type sendMessageRequest struct {
+	ChatID          string `json:"chat_id"`
+	Text            string `json:"text"`
+	MessageThreadID string `json:"message_thread_id,omitempty"`
+	ParseMode       string `json:"parse_mode,omitempty"`
+}
+
+type editMessageTextRequest struct {
+	ChatID    string `json:"chat_id"`
+	MessageID int    `json:"message_id"`
+	Text      string `json:"text"`
+	ParseMode string `json:"parse_mode,omitempty"`
+}
+
+type apiResponse struct {
+	OK     bool `json:"ok"`
+	Result struct {
+		MessageID int `json:"message_id"`
+	} `json:"result"`
+}
+
+// Send delivers text to the configured chat as a new message, retrying
+// transient failures up to cfg.Retries times. threadID addresses a forum
+// topic within the chat and may be empty, as may parseMode ("", "MarkdownV2"
+// or "HTML").
+//
+// Calls are gated by a circuit breaker keyed to this Client's chat (see
+// withRetry): once cfg.BreakerThreshold consecutive 429/5xx/timeout
+// failures have been seen, further sends are short-circuited for
+// cfg.BreakerCooldown (or Telegram's own retry_after, if it sent one)
+// instead of spending retry budget against a chat that is clearly not
+// receiving messages right now.
+//
+// The returned int is the number of retries beyond the first attempt that
+// were actually made (0 if it succeeded immediately, or if the circuit
+// breaker short-circuited the call before any request was sent), for the
+// caller to fold into its own retry accounting.
+func (c *Client) Send(ctx context.Context, text, threadID, parseMode string) (int, error) {
+	_, retries, err := c.withRetry(func() (int, error) {
+		return c.sendMessage(ctx, text, threadID, parseMode)
+	})
+	return retries, err
+}
+
+// SendStreaming appends text to the driver's running "tail" message for
+// this chat, editing it in place via editMessageText when doing so stays
+// within rolloverThreshold characters and at least maxEditFrequency has
+// elapsed since the last edit. Otherwise it starts a fresh tail message,
+// mirroring Telegram's 4096-character message limit and rate limits on
+// message edits.
+//
+// The returned int is the number of retries beyond the first attempt that
+// were actually made, as for Send.
+func (c *Client) SendStreaming(ctx context.Context, text, threadID, parseMode string, maxEditFrequency time.Duration, rolloverThreshold int) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.tailID != 0 {
+		candidate := c.tailText + text
+		if len(candidate) <= rolloverThreshold {
+			if time.Since(c.lastEdit) < maxEditFrequency {
+				// Too soon to send another edit without risking Telegram's
+				// rate limit; fold the line in and edit on the next call.
+				c.tailText = candidate
+				return 0, nil
+			}
+
+			_, retries, err := c.withRetry(func() (int, error) {
+				return 0, c.editMessage(ctx, c.tailID, candidate, parseMode)
+			})
+			if err != nil {
+				return retries, err
+			}
+
+			c.tailText = candidate
+			c.lastEdit = time.Now()
+
+			return retries, nil
+		}
+	}
+
+	id, retries, err := c.withRetry(func() (int, error) {
+		return c.sendMessage(ctx, text, threadID, parseMode)
+	})
+	if err != nil {
+		return retries, err
+	}
+
+	c.tailID = id
+	c.tailText = text
+	c.lastEdit = time.Now()
+
+	return retries, nil
+}
+
+// withRetry gates fn behind c.breaker and retries it up to c.cfg.Retries
+// times, recording the outcome against the breaker so that Send and
+// SendStreaming share the same circuit-breaker protection. The returned
+// retries count is how many attempts beyond the first were actually made
+// (0 when the breaker short-circuits fn entirely).
+func (c *Client) withRetry(fn func() (int, error)) (id int, retries int, err error) {
+	if !c.breaker.allow() {
+		return 0, 0, fmt.Errorf("circuit breaker open for chat %q", c.cfg.ChatID)
+	}
+
+	var lastErr error
+
+	for attempt := 0; attempt <= c.cfg.Retries; attempt++ {
+		id, err := fn()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		c.breaker.recordSuccess()
+		return id, attempt, nil
+	}
+
+	countable, retryAfter := breakerFailure(lastErr)
+	c.breaker.recordFailure(countable, retryAfter)
+
+	return 0, c.cfg.Retries, fmt.Errorf("failed after %d attempts: %w", c.cfg.Retries+1, lastErr)
+}
+
+func (c *Client) sendMessage(ctx context.Context, text, threadID, parseMode string) (int, error) {
+	body, err := json.Marshal(sendMessageRequest{
+		ChatID:          c.cfg.ChatID,
+		Text:            text,
+		MessageThreadID: threadID,
+		ParseMode:       parseMode,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	resp, err := c.do(ctx, "sendMessage", body)
+	if err != nil {
+		return 0, err
+	}
+
+	return resp.Result.MessageID, nil
+}
+
+func (c *Client) editMessage(ctx context.Context, messageID int, text, parseMode string) error {
+	body, err := json.Marshal(editMessageTextRequest{
+		ChatID:    c.cfg.ChatID,
+		MessageID: messageID,
+		Text:      text,
+		ParseMode: parseMode,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	_, err = c.do(ctx, "editMessageText", body)
+	return err
+}
+
+func (c *Client) do(ctx context.Context, method string, body []byte) (*apiResponse, error) {
+	url := fmt.Sprintf("%s/bot%s/%s", c.cfg.APIURL, c.cfg.Token, method)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		var netErr net.Error
+		timeout := errors.As(err, &netErr) && netErr.Timeout()
+		return nil, &apiCallError{Timeout: timeout, Err: fmt.Errorf("failed to send request: %w", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp struct {
+			Parameters struct {
+				RetryAfter int `json:"retry_after"`
+			} `json:"parameters"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&errResp)
+
+		return nil, &apiCallError{
+			StatusCode: resp.StatusCode,
+			RetryAfter: time.Duration(errResp.Parameters.RetryAfter) * time.Second,
+			Err:        fmt.Errorf("unexpected status code %d", resp.StatusCode),
+		}
+	}
+
+	var out apiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &out, nil
+}
+
+// apiCallError carries the extra detail Client.Send's circuit breaker needs
+// to judge whether a failure counts against it: Telegram's HTTP status (for
+// 429/5xx), whether the failure was a timeout, and, for a 429, the
+// retry_after it asked us to wait.
+type apiCallError struct {
+	StatusCode int
+	Timeout    bool
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *apiCallError) Error() string { return e.Err.Error() }
+func (e *apiCallError) Unwrap() error { return e.Err }
+
+// breakerFailure reports whether err is the kind of failure that should
+// count against a Client's circuit breaker (429, 5xx, or a timeout), and
+// the cooldown Telegram requested via retry_after, if any.
+func breakerFailure(err error) (countable bool, retryAfter time.Duration) {
+	var apiErr *apiCallError
+	if !errors.As(err, &apiErr) {
+		return false, 0
+	}
+
+	if apiErr.Timeout || apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= 500 {
+		return true, apiErr.RetryAfter
+	}
+
+	return false, 0
+}
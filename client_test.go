@@ -0,0 +1,329 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientSendStreaming(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var calls []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, "sendMessage"):
+			calls = append(calls, "send")
+		case strings.HasSuffix(r.URL.Path, "editMessageText"):
+			calls = append(calls, "edit")
+		}
+
+		_ = json.NewEncoder(w).Encode(apiResponse{OK: true, Result: struct {
+			MessageID int `json:"message_id"`
+		}{MessageID: 1}})
+	}))
+	defer server.Close()
+
+	client, err := NewClient(ClientConfig{
+		APIURL:  server.URL,
+		Token:   "token",
+		ChatID:  "chat_id",
+		Retries: 1,
+		Timeout: time.Second,
+	})
+	require.NoError(t, err)
+
+	// First call has no tail message yet, so it must send.
+	_, err = client.SendStreaming(context.Background(), "line one\n", "", "", 0, 100)
+	require.NoError(t, err)
+
+	// Appending stays within the rollover threshold and enough time has
+	// passed (maxEditFrequency is 0), so this must edit the tail message.
+	_, err = client.SendStreaming(context.Background(), "line two\n", "", "", 0, 100)
+	require.NoError(t, err)
+
+	// Appending would exceed the rollover threshold, so this must roll
+	// over to a new tail message.
+	_, err = client.SendStreaming(context.Background(), strings.Repeat("x", 100), "", "", 0, 100)
+	require.NoError(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"send", "edit", "send"}, calls)
+}
+
+func TestClientSendStreamingRespectsEditFrequency(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var calls []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, "sendMessage"):
+			calls = append(calls, "send")
+		case strings.HasSuffix(r.URL.Path, "editMessageText"):
+			calls = append(calls, "edit")
+		}
+
+		_ = json.NewEncoder(w).Encode(apiResponse{OK: true, Result: struct {
+			MessageID int `json:"message_id"`
+		}{MessageID: 1}})
+	}))
+	defer server.Close()
+
+	client, err := NewClient(ClientConfig{
+		APIURL:  server.URL,
+		Token:   "token",
+		ChatID:  "chat_id",
+		Retries: 1,
+		Timeout: time.Second,
+	})
+	require.NoError(t, err)
+
+	_, err = client.SendStreaming(context.Background(), "line one\n", "", "", time.Minute, 100)
+	require.NoError(t, err)
+
+	// Edit would come too soon to respect maxEditFrequency, so it must be
+	// folded into the buffered tail instead of calling the API again.
+	_, err = client.SendStreaming(context.Background(), "line two\n", "", "", time.Minute, 100)
+	require.NoError(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"send"}, calls)
+}
+
+func TestClientSendThroughHTTPProxy(t *testing.T) {
+	t.Parallel()
+
+	var requestedURL string
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestedURL = r.URL.String()
+
+		_ = json.NewEncoder(w).Encode(apiResponse{OK: true, Result: struct {
+			MessageID int `json:"message_id"`
+		}{MessageID: 1}})
+	}))
+	defer proxy.Close()
+
+	client, err := NewClient(ClientConfig{
+		APIURL:   "http://telegram.example",
+		Token:    "token",
+		ChatID:   "chat_id",
+		Retries:  1,
+		Timeout:  time.Second,
+		ProxyURL: proxy.URL,
+	})
+	require.NoError(t, err)
+
+	_, err = client.Send(context.Background(), "hello", "", "")
+	require.NoError(t, err)
+	assert.Contains(t, requestedURL, "telegram.example")
+}
+
+func TestClientSendThroughSOCKS5Proxy(t *testing.T) {
+	t.Parallel()
+
+	var requestedURL string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestedURL = r.URL.String()
+
+		_ = json.NewEncoder(w).Encode(apiResponse{OK: true, Result: struct {
+			MessageID int `json:"message_id"`
+		}{MessageID: 1}})
+	}))
+	defer server.Close()
+
+	proxyAddr := newSOCKS5StubServer(t, server.Listener.Addr().String())
+
+	client, err := NewClient(ClientConfig{
+		APIURL:   server.URL,
+		Token:    "token",
+		ChatID:   "chat_id",
+		Retries:  1,
+		Timeout:  time.Second,
+		ProxyURL: "socks5://" + proxyAddr,
+	})
+	require.NoError(t, err)
+
+	_, err = client.Send(context.Background(), "hello", "", "")
+	require.NoError(t, err)
+	assert.NotEmpty(t, requestedURL)
+}
+
+// newSOCKS5StubServer starts a minimal SOCKS5 server (no auth, CONNECT
+// only) that ignores the requested address and always tunnels to target,
+// then returns the stub's listen address. It's just enough of the
+// protocol to prove newProxyTransport's socks5 dialer actually performs a
+// SOCKS5 handshake end-to-end, rather than only being reachable via a
+// parser-level config test.
+func newSOCKS5StubServer(t *testing.T, target string) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// Greeting: VER, NMETHODS, METHODS...
+		greeting := make([]byte, 2)
+		if _, err := io.ReadFull(conn, greeting); err != nil {
+			return
+		}
+		methods := make([]byte, greeting[1])
+		if _, err := io.ReadFull(conn, methods); err != nil {
+			return
+		}
+		if _, err := conn.Write([]byte{0x05, 0x00}); err != nil { // no auth required
+			return
+		}
+
+		// Request: VER, CMD, RSV, ATYP, DST.ADDR, DST.PORT.
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		switch header[3] {
+		case 0x01: // IPv4
+			addr := make([]byte, 4+2)
+			if _, err := io.ReadFull(conn, addr); err != nil {
+				return
+			}
+		case 0x03: // domain name
+			length := make([]byte, 1)
+			if _, err := io.ReadFull(conn, length); err != nil {
+				return
+			}
+			addr := make([]byte, int(length[0])+2)
+			if _, err := io.ReadFull(conn, addr); err != nil {
+				return
+			}
+		default:
+			return
+		}
+
+		upstream, err := net.Dial("tcp", target)
+		if err != nil {
+			return
+		}
+		defer upstream.Close()
+
+		// Reply success with a dummy bound address, per RFC 1928.
+		if _, err := conn.Write([]byte{0x05, 0x00, 0x00, 0x01, 0, 0, 0, 0, 0, 0}); err != nil {
+			return
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() { defer wg.Done(); _, _ = io.Copy(upstream, conn) }()
+		go func() { defer wg.Done(); _, _ = io.Copy(conn, upstream) }()
+		wg.Wait()
+	}()
+
+	return ln.Addr().String()
+}
+
+func TestClientSendOpensBreakerOnRepeated429(t *testing.T) {
+	t.Parallel()
+
+	var calls int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusTooManyRequests)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"ok":          false,
+			"description": "Too Many Requests",
+			"parameters":  map[string]int{"retry_after": 1},
+		})
+	}))
+	defer server.Close()
+
+	client, err := NewClient(ClientConfig{
+		APIURL:           server.URL,
+		Token:            "token",
+		ChatID:           "chat_id",
+		Retries:          0,
+		Timeout:          time.Second,
+		BreakerThreshold: 1,
+		BreakerCooldown:  time.Minute,
+	})
+	require.NoError(t, err)
+
+	_, err = client.Send(context.Background(), "hello", "", "")
+	require.Error(t, err)
+	callsAfterFirstFailure := calls
+
+	// The breaker is now open; a second Send must be short-circuited
+	// without reaching the server at all.
+	_, err = client.Send(context.Background(), "hello again", "", "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "circuit breaker open")
+	assert.Equal(t, callsAfterFirstFailure, calls)
+}
+
+func TestClientSendStreamingOpensBreakerOnRepeated429(t *testing.T) {
+	t.Parallel()
+
+	var calls int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusTooManyRequests)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"ok":          false,
+			"description": "Too Many Requests",
+			"parameters":  map[string]int{"retry_after": 1},
+		})
+	}))
+	defer server.Close()
+
+	client, err := NewClient(ClientConfig{
+		APIURL:           server.URL,
+		Token:            "token",
+		ChatID:           "chat_id",
+		Retries:          0,
+		Timeout:          time.Second,
+		BreakerThreshold: 1,
+		BreakerCooldown:  time.Minute,
+	})
+	require.NoError(t, err)
+
+	_, err = client.SendStreaming(context.Background(), "hello", "", "", 0, 100)
+	require.Error(t, err)
+	callsAfterFirstFailure := calls
+
+	// The breaker is now open; a second SendStreaming call must be
+	// short-circuited without reaching the server at all.
+	_, err = client.SendStreaming(context.Background(), "hello again", "", "", 0, 100)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "circuit breaker open")
+	assert.Equal(t, callsAfterFirstFailure, calls)
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+)
+
+// LogMessage represents a single line emitted by a container.
+type LogMessage struct {
+	ContainerID   string
+	ContainerName string
+	Log           string
+	Timestamp     time.Time
+}
+
+// LogStats counts what a logger has done with the messages handed to it.
+type LogStats struct {
+	Delivered uint64
+	Dropped   uint64
+	Retried   uint64
+}
+
+// logger buffers and delivers the messages for a single container to the
+// configured Telegram chat.
+//
+// In logModeBlocking (the default) Log blocks the caller until there is
+// room in the buffer, mirroring Docker's own blocking log driver contract.
+// In logModeNonBlocking Log never blocks: once the buffer reaches
+// cfg.LogMaxBufferSize the oldest queued message is dropped to make room,
+// and the drop is counted in stats rather than surfaced to the caller.
+type logger struct {
+	cfg      loggerConfig
+	client   *Client
+	renderer Renderer
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	buf    []LogMessage
+	closed bool
+	tick   bool
+
+	routeMu      sync.Mutex
+	routeClients map[string]*Client
+
+	stats LogStats
+}
+
+func newLogger(cfg loggerConfig, client *Client) (*logger, error) {
+	tmpl, err := template.New("message").Parse(cfg.Template)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	renderer, err := newRenderer(cfg.Format, tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build renderer: %w", err)
+	}
+
+	l := &logger{
+		cfg:          cfg,
+		client:       client,
+		renderer:     renderer,
+		routeClients: make(map[string]*Client),
+	}
+	l.cond = sync.NewCond(&l.mu)
+
+	return l, nil
+}
+
+// Log enqueues msg for delivery. It honours cfg.FilterRegex and the
+// configured log-mode's backpressure policy.
+func (l *logger) Log(msg LogMessage) error {
+	if l.cfg.FilterRegex != nil && !l.cfg.FilterRegex.MatchString(msg.Log) {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return fmt.Errorf("logger is closed")
+	}
+
+	for len(l.buf) >= l.cfg.LogMaxBufferSize {
+		if l.cfg.LogMode == logModeNonBlocking {
+			l.buf = l.buf[1:]
+			atomic.AddUint64(&l.stats.Dropped, 1)
+			break
+		}
+
+		l.cond.Wait()
+		if l.closed {
+			return fmt.Errorf("logger is closed")
+		}
+	}
+
+	l.buf = append(l.buf, msg)
+	l.cond.Signal()
+
+	return nil
+}
+
+// run drains the buffer and delivers messages until Close is called. It is
+// meant to be run in its own goroutine.
+func (l *logger) run() {
+	stop := make(chan struct{})
+	if l.cfg.BatchEnabled {
+		go l.tickLoop(stop)
+		defer close(stop)
+	}
+
+	var batch []LogMessage
+
+	for {
+		msg, tick, ok := l.next()
+		if !ok {
+			l.flush(batch)
+			return
+		}
+
+		if !l.cfg.BatchEnabled {
+			l.flush([]LogMessage{msg})
+			continue
+		}
+
+		if tick {
+			l.flush(batch)
+			batch = nil
+			continue
+		}
+
+		batch = append(batch, msg)
+	}
+}
+
+// next blocks until a message is available, a batch-flush tick fires, or
+// the logger is closed with nothing left to deliver.
+func (l *logger) next() (msg LogMessage, tick bool, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for len(l.buf) == 0 && !l.closed && !l.tick {
+		l.cond.Wait()
+	}
+
+	if l.tick {
+		l.tick = false
+		return LogMessage{}, true, true
+	}
+
+	if len(l.buf) == 0 {
+		return LogMessage{}, false, false
+	}
+
+	msg = l.buf[0]
+	l.buf = l.buf[1:]
+	l.cond.Signal()
+
+	return msg, false, true
+}
+
+func (l *logger) tickLoop(stop chan struct{}) {
+	ticker := time.NewTicker(l.cfg.BatchFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			l.mu.Lock()
+			l.tick = true
+			l.cond.Broadcast()
+			l.mu.Unlock()
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (l *logger) flush(batch []LogMessage) {
+	for _, msg := range batch {
+		text, parseMode, err := l.renderer.Render(msg)
+		if err != nil {
+			continue
+		}
+
+		client, threadID := l.routeFor(msg)
+		retries, err := l.deliver(client, text, threadID, parseMode)
+		atomic.AddUint64(&l.stats.Retried, uint64(retries))
+		if err != nil {
+			continue
+		}
+
+		atomic.AddUint64(&l.stats.Delivered, 1)
+	}
+}
+
+// deliver sends a single rendered line through client, using the streaming
+// edit-message mode when enabled and falling back to one Telegram message
+// per line otherwise. The returned int is the number of retries the send
+// actually took, regardless of whether it ultimately succeeded.
+func (l *logger) deliver(client *Client, text, threadID, parseMode string) (int, error) {
+	if l.cfg.StreamingEnabled {
+		return client.SendStreaming(context.Background(), text, threadID, parseMode, l.cfg.MaxEditFrequency, l.cfg.RolloverThreshold)
+	}
+
+	return client.Send(context.Background(), text, threadID, parseMode)
+}
+
+// routeFor picks the Client and message-thread ID that msg should be sent
+// through: the first matching entry in cfg.Routes, or the container's
+// default client and thread when nothing matches.
+func (l *logger) routeFor(msg LogMessage) (*Client, string) {
+	for _, route := range l.cfg.Routes {
+		if route.Regex.MatchString(msg.Log) {
+			return l.routeClient(route), route.MessageThreadID
+		}
+	}
+
+	return l.client, l.cfg.MessageThreadID
+}
+
+// routeClient returns the (lazily created, cached) Client for route,
+// reusing the default client's connection settings but overriding the
+// chat/topic it posts to.
+func (l *logger) routeClient(route Route) *Client {
+	key := route.ChatID + "/" + route.MessageThreadID
+
+	l.routeMu.Lock()
+	defer l.routeMu.Unlock()
+
+	if c, ok := l.routeClients[key]; ok {
+		return c
+	}
+
+	clientCfg := l.cfg.ClientConfig
+	clientCfg.ChatID = route.ChatID
+
+	c, err := NewClient(clientCfg)
+	if err != nil {
+		// clientCfg carries the same ProxyURL that l.client was built from
+		// successfully, so this can't actually fail; fall back to the
+		// default client rather than letting a route silently vanish.
+		return l.client
+	}
+	l.routeClients[key] = c
+
+	return c
+}
+
+// Close stops run and wakes any producer blocked in Log.
+func (l *logger) Close() {
+	l.mu.Lock()
+	l.closed = true
+	l.cond.Broadcast()
+	l.mu.Unlock()
+}
+
+// Metrics returns a snapshot of this logger's delivery counters.
+func (l *logger) Metrics() LogStats {
+	return LogStats{
+		Delivered: atomic.LoadUint64(&l.stats.Delivered),
+		Dropped:   atomic.LoadUint64(&l.stats.Dropped),
+		Retried:   atomic.LoadUint64(&l.stats.Retried),
+	}
+}
@@ -0,0 +1,421 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ContainerDetails carries the subset of the Docker logging plugin's
+// StartLogging request that the driver needs in order to configure itself
+// for a given container.
+type ContainerDetails struct {
+	ContainerID string
+	Config      map[string]string
+}
+
+const (
+	cfgTokenKey              = "token"
+	cfgChatIDKey             = "chat-id"
+	cfgURLKey                = "api-url"
+	cfgRetriesKey            = "retries"
+	cfgTimeoutKey            = "timeout"
+	cfgTemplateKey           = "template"
+	cfgFilterRegexKey        = "filter-regex"
+	cfgLabelsRegexKey        = "labels-regex"
+	cfgMaxBufferSizeKey      = "max-buffer-size"
+	cfgMessageThreadIDKey    = "message-thread-id"
+	cfgBatchEnabledKey       = "batch"
+	cfgBatchFlushIntervalKey = "batch-flush-interval"
+	cfgLogModeKey            = "log-mode"
+	cfgLogMaxBufferSizeKey   = "log-max-buffer-size"
+	cfgStreamingKey          = "streaming"
+	cfgMaxEditFrequencyKey   = "max-edit-frequency"
+	cfgRolloverThresholdKey  = "rollover-threshold"
+	cfgChatRoutesKey         = "chat-routes"
+	cfgFormatKey             = "format"
+	cfgProxyURLKey           = "proxy-url"
+	cfgBreakerThresholdKey   = "breaker-threshold"
+	cfgBreakerCooldownKey    = "breaker-cooldown"
+)
+
+const (
+	formatText       = "text"
+	formatJSON       = "json"
+	formatLogfmt     = "logfmt"
+	formatMarkdownV2 = "markdown-v2"
+	formatHTML       = "html"
+)
+
+// telegramMessageLimit is the maximum length, in characters, of a single
+// Telegram message; a streaming tail message must roll over before it
+// would exceed this.
+const telegramMessageLimit = 4096
+
+const (
+	logModeBlocking    = "blocking"
+	logModeNonBlocking = "non-blocking"
+)
+
+// ClientConfig holds everything the Telegram client needs to reach the Bot
+// API, independent of any single container's logging options.
+type ClientConfig struct {
+	APIURL   string
+	Token    string
+	ChatID   string
+	Retries  int
+	Timeout  time.Duration
+	ProxyURL string
+
+	// BreakerThreshold is the number of consecutive 429/5xx/timeout
+	// failures that open the circuit breaker for this chat; BreakerCooldown
+	// is how long it then stays open before a single half-open probe is
+	// allowed through.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+var defaultClientConfig = ClientConfig{
+	APIURL:           "https://api.telegram.org",
+	Retries:          3,
+	Timeout:          10 * time.Second,
+	BreakerThreshold: 5,
+	BreakerCooldown:  30 * time.Second,
+}
+
+// loggerConfig is the fully parsed, per-container configuration for a
+// logger instance.
+type loggerConfig struct {
+	ClientConfig
+
+	Attrs           map[string]string
+	Template        string
+	FilterRegex     *regexp.Regexp
+	MaxBufferSize   int64
+	MessageThreadID string
+
+	BatchEnabled       bool
+	BatchFlushInterval time.Duration
+
+	LogMode          string
+	LogMaxBufferSize int
+
+	StreamingEnabled  bool
+	MaxEditFrequency  time.Duration
+	RolloverThreshold int
+
+	Routes []Route
+
+	Format string
+}
+
+// Route sends lines matching Regex to a chat/topic other than the
+// container's default one, e.g. routing ERROR lines to an on-call topic
+// while INFO stays in the general chat. Routes are evaluated in order and
+// the first match wins; a line matching no route falls back to the
+// default ClientConfig.ChatID.
+type Route struct {
+	Regex           *regexp.Regexp
+	ChatID          string
+	MessageThreadID string
+}
+
+var defaultLoggerConfig = loggerConfig{
+	Template:           "{{.Log}}",
+	MaxBufferSize:      1 * 1024 * 1024, // 1MB
+	BatchEnabled:       false,
+	BatchFlushInterval: 5 * time.Second,
+	LogMode:            logModeBlocking,
+	LogMaxBufferSize:   1000,
+	StreamingEnabled:   false,
+	MaxEditFrequency:   3 * time.Second,
+	RolloverThreshold:  4000,
+	Format:             formatText,
+}
+
+// parseClientConfig extracts the Telegram client options out of a
+// container's log options, falling back to defaultClientConfig for
+// anything the user didn't set.
+func parseClientConfig(cd *ContainerDetails) (ClientConfig, error) {
+	cfg := defaultClientConfig
+	cfg.Token = cd.Config[cfgTokenKey]
+	cfg.ChatID = cd.Config[cfgChatIDKey]
+
+	if v, ok := cd.Config[cfgURLKey]; ok {
+		cfg.APIURL = v
+	}
+
+	if v, ok := cd.Config[cfgRetriesKey]; ok {
+		retries, err := strconv.Atoi(v)
+		if err != nil {
+			return ClientConfig{}, fmt.Errorf("failed to parse %q option: %w", cfgRetriesKey, err)
+		}
+		if retries < 0 {
+			return ClientConfig{}, fmt.Errorf("invalid %q option: must not be negative", cfgRetriesKey)
+		}
+		cfg.Retries = retries
+	}
+
+	if v, ok := cd.Config[cfgTimeoutKey]; ok {
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			return ClientConfig{}, fmt.Errorf("failed to parse %q option: %w", cfgTimeoutKey, err)
+		}
+		cfg.Timeout = timeout
+	}
+
+	if v, ok := cd.Config[cfgProxyURLKey]; ok {
+		u, err := url.Parse(v)
+		if err != nil {
+			return ClientConfig{}, fmt.Errorf("failed to parse %q option: %w", cfgProxyURLKey, err)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return ClientConfig{}, fmt.Errorf("invalid %q option: unsupported scheme %q", cfgProxyURLKey, u.Scheme)
+		}
+		cfg.ProxyURL = v
+	}
+
+	if v, ok := cd.Config[cfgBreakerThresholdKey]; ok {
+		threshold, err := strconv.Atoi(v)
+		if err != nil {
+			return ClientConfig{}, fmt.Errorf("failed to parse %q option: %w", cfgBreakerThresholdKey, err)
+		}
+		if threshold <= 0 {
+			return ClientConfig{}, fmt.Errorf("invalid %q option: must be a positive integer", cfgBreakerThresholdKey)
+		}
+		cfg.BreakerThreshold = threshold
+	}
+
+	if v, ok := cd.Config[cfgBreakerCooldownKey]; ok {
+		cooldown, err := time.ParseDuration(v)
+		if err != nil {
+			return ClientConfig{}, fmt.Errorf("failed to parse %q option: %w", cfgBreakerCooldownKey, err)
+		}
+		if cooldown <= 0 {
+			return ClientConfig{}, fmt.Errorf("invalid %q option: must be positive", cfgBreakerCooldownKey)
+		}
+		cfg.BreakerCooldown = cooldown
+	}
+
+	return cfg, nil
+}
+
+// parseLoggerConfig builds a loggerConfig from the options Docker passes to
+// the plugin when a container starts logging.
+func parseLoggerConfig(cd *ContainerDetails) (*loggerConfig, error) {
+	clientCfg, err := parseClientConfig(cd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse client config: %w", err)
+	}
+
+	attrs, err := parseExtraAttributes(cd.Config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse extra attributes: %w", err)
+	}
+
+	cfg := defaultLoggerConfig
+	cfg.ClientConfig = clientCfg
+	cfg.Attrs = attrs
+
+	if v, ok := cd.Config[cfgTemplateKey]; ok {
+		cfg.Template = v
+	}
+
+	if v, ok := cd.Config[cfgFilterRegexKey]; ok {
+		re, err := regexp.Compile(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %q option: %w", cfgFilterRegexKey, err)
+		}
+		cfg.FilterRegex = re
+	}
+
+	if v, ok := cd.Config[cfgMaxBufferSizeKey]; ok {
+		size, err := parseBufferSize(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %q option: %w", cfgMaxBufferSizeKey, err)
+		}
+		cfg.MaxBufferSize = size
+	}
+
+	if v, ok := cd.Config[cfgMessageThreadIDKey]; ok {
+		cfg.MessageThreadID = v
+	}
+
+	if v, ok := cd.Config[cfgBatchEnabledKey]; ok {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %q option: %w", cfgBatchEnabledKey, err)
+		}
+		cfg.BatchEnabled = enabled
+	}
+
+	if v, ok := cd.Config[cfgBatchFlushIntervalKey]; ok {
+		interval, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %q option: %w", cfgBatchFlushIntervalKey, err)
+		}
+		cfg.BatchFlushInterval = interval
+	}
+
+	if v, ok := cd.Config[cfgLogModeKey]; ok {
+		if v != logModeBlocking && v != logModeNonBlocking {
+			return nil, fmt.Errorf("invalid %q option: must be %q or %q", cfgLogModeKey, logModeBlocking, logModeNonBlocking)
+		}
+		cfg.LogMode = v
+	}
+
+	if v, ok := cd.Config[cfgLogMaxBufferSizeKey]; ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %q option: %w", cfgLogMaxBufferSizeKey, err)
+		}
+		if n <= 0 {
+			return nil, fmt.Errorf("invalid %q option: must be a positive integer", cfgLogMaxBufferSizeKey)
+		}
+		cfg.LogMaxBufferSize = n
+	}
+
+	if v, ok := cd.Config[cfgStreamingKey]; ok {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %q option: %w", cfgStreamingKey, err)
+		}
+		cfg.StreamingEnabled = enabled
+	}
+
+	if v, ok := cd.Config[cfgMaxEditFrequencyKey]; ok {
+		freq, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %q option: %w", cfgMaxEditFrequencyKey, err)
+		}
+		cfg.MaxEditFrequency = freq
+	}
+
+	if v, ok := cd.Config[cfgRolloverThresholdKey]; ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %q option: %w", cfgRolloverThresholdKey, err)
+		}
+		if n <= 0 || n > telegramMessageLimit {
+			return nil, fmt.Errorf("invalid %q option: must be between 1 and %d", cfgRolloverThresholdKey, telegramMessageLimit)
+		}
+		cfg.RolloverThreshold = n
+	}
+
+	if v, ok := cd.Config[cfgChatRoutesKey]; ok {
+		routes, err := parseChatRoutes(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %q option: %w", cfgChatRoutesKey, err)
+		}
+		cfg.Routes = routes
+	}
+
+	if v, ok := cd.Config[cfgFormatKey]; ok {
+		switch v {
+		case formatText, formatJSON, formatLogfmt, formatMarkdownV2, formatHTML:
+			cfg.Format = v
+		default:
+			return nil, fmt.Errorf("invalid %q option: %q", cfgFormatKey, v)
+		}
+	}
+
+	return &cfg, nil
+}
+
+// parseChatRoutes parses a "chat-routes" option of the form
+// "regex=ERROR,chat=-100123,thread=42;regex=WARN,chat=-100456" into a
+// slice of Routes, evaluated in order by the sender.
+func parseChatRoutes(s string) ([]Route, error) {
+	var routes []Route
+
+	for _, entry := range strings.Split(s, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		var route Route
+		var rawRegex string
+
+		for _, field := range strings.Split(entry, ",") {
+			key, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid route field %q: expected key=value", field)
+			}
+
+			switch key {
+			case "regex":
+				rawRegex = value
+			case "chat":
+				route.ChatID = value
+			case "thread":
+				route.MessageThreadID = value
+			default:
+				return nil, fmt.Errorf("unknown route field %q", key)
+			}
+		}
+
+		if rawRegex == "" {
+			return nil, fmt.Errorf("route %q is missing a %q field", entry, "regex")
+		}
+		if route.ChatID == "" {
+			return nil, fmt.Errorf("route %q is missing a %q field", entry, "chat")
+		}
+
+		re, err := regexp.Compile(rawRegex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex %q: %w", rawRegex, err)
+		}
+		route.Regex = re
+
+		routes = append(routes, route)
+	}
+
+	return routes, nil
+}
+
+// parseExtraAttributes pulls the freeform, user-supplied attributes (e.g.
+// container labels selected via a regex) out of the raw config map.
+func parseExtraAttributes(config map[string]string) (map[string]string, error) {
+	attrs := make(map[string]string)
+
+	if v, ok := config[cfgLabelsRegexKey]; ok {
+		if _, err := regexp.Compile(v); err != nil {
+			return nil, err
+		}
+	}
+
+	return attrs, nil
+}
+
+// parseBufferSize parses sizes like "100MB" or a plain byte count.
+func parseBufferSize(s string) (int64, error) {
+	multiplier := int64(1)
+
+	switch {
+	case strings.HasSuffix(s, "GB"):
+		multiplier = 1024 * 1024 * 1024
+		s = strings.TrimSuffix(s, "GB")
+	case strings.HasSuffix(s, "MB"):
+		multiplier = 1024 * 1024
+		s = strings.TrimSuffix(s, "MB")
+	case strings.HasSuffix(s, "KB"):
+		multiplier = 1024
+		s = strings.TrimSuffix(s, "KB")
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if n <= 0 {
+		return 0, fmt.Errorf("must be a positive integer")
+	}
+
+	return n * multiplier, nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoggerRouteFor(t *testing.T) {
+	t.Parallel()
+
+	cfg := defaultLoggerConfig
+	cfg.ClientConfig = defaultClientConfig
+	cfg.ClientConfig.ChatID = "general"
+	cfg.Routes = []Route{
+		{Regex: regexp.MustCompile("ERROR"), ChatID: "oncall", MessageThreadID: "7"},
+	}
+
+	client, err := NewClient(cfg.ClientConfig)
+	require.NoError(t, err)
+
+	l, err := newLogger(cfg, client)
+	require.NoError(t, err)
+
+	client, threadID := l.routeFor(LogMessage{Log: "ERROR: disk full"})
+	assert.Equal(t, "oncall", client.cfg.ChatID)
+	assert.Equal(t, "7", threadID)
+
+	// A line matching no route falls back to the container's default chat.
+	fallbackClient, fallbackThreadID := l.routeFor(LogMessage{Log: "INFO: started"})
+	assert.Same(t, l.client, fallbackClient)
+	assert.Equal(t, cfg.MessageThreadID, fallbackThreadID)
+
+	// Routing to the same chat/thread twice reuses the cached Client.
+	again, _ := l.routeFor(LogMessage{Log: "ERROR: still full"})
+	assert.Same(t, client, again)
+}
+
+func TestLoggerNonBlockingDropsOldest(t *testing.T) {
+	t.Parallel()
+
+	cfg := defaultLoggerConfig
+	cfg.ClientConfig = defaultClientConfig
+	cfg.LogMode = logModeNonBlocking
+	cfg.LogMaxBufferSize = 2
+
+	client, err := NewClient(cfg.ClientConfig)
+	require.NoError(t, err)
+
+	l, err := newLogger(cfg, client)
+	require.NoError(t, err)
+
+	// Nothing is draining the buffer (run isn't started), so the third
+	// Log call must drop the oldest entry rather than block.
+	require.NoError(t, l.Log(LogMessage{Log: "one"}))
+	require.NoError(t, l.Log(LogMessage{Log: "two"}))
+	require.NoError(t, l.Log(LogMessage{Log: "three"}))
+
+	l.mu.Lock()
+	buf := append([]LogMessage(nil), l.buf...)
+	l.mu.Unlock()
+
+	require.Len(t, buf, 2)
+	assert.Equal(t, "two", buf[0].Log)
+	assert.Equal(t, "three", buf[1].Log)
+	assert.Equal(t, uint64(1), l.Metrics().Dropped)
+}
+
+func TestLoggerFlushTracksActualRetries(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var calls int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		n := calls
+		mu.Unlock()
+
+		if n <= 2 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1}}`))
+	}))
+	defer server.Close()
+
+	cfg := defaultLoggerConfig
+	cfg.ClientConfig = defaultClientConfig
+	cfg.ClientConfig.ChatID = "chat_id"
+	cfg.ClientConfig.APIURL = server.URL
+
+	client, err := NewClient(cfg.ClientConfig)
+	require.NoError(t, err)
+
+	l, err := newLogger(cfg, client)
+	require.NoError(t, err)
+
+	// The first two attempts fail and the third succeeds, so this message
+	// must be delivered and must count exactly 2 retries - not
+	// cfg.Retries (3), and not 0.
+	l.flush([]LogMessage{{Log: "line one"}})
+
+	assert.Equal(t, uint64(1), l.Metrics().Delivered)
+	assert.Equal(t, uint64(2), l.Metrics().Retried)
+
+	// A message that succeeds on the first attempt must not add to Retried.
+	l.flush([]LogMessage{{Log: "line two"}})
+
+	assert.Equal(t, uint64(2), l.Metrics().Delivered)
+	assert.Equal(t, uint64(2), l.Metrics().Retried)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+)
+
+// pluginSocketPath is where the Docker daemon expects this plugin's API to
+// be listening, per the logging plugin protocol.
+const pluginSocketPath = "/run/docker/plugins/telegram.sock"
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	d := newDriver()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", d.ServeMetrics)
+	mux.HandleFunc("/LogDriver.StartLogging", d.handleStartLogging)
+	mux.HandleFunc("/LogDriver.StopLogging", d.handleStopLogging)
+
+	_ = os.Remove(pluginSocketPath)
+	listener, err := net.Listen("unix", pluginSocketPath)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %q: %w", pluginSocketPath, err)
+	}
+
+	return http.Serve(listener, mux)
+}
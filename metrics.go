@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// driver tracks the loggers for all currently logging containers and
+// exposes their delivery counters over /metrics.
+type driver struct {
+	mu      sync.Mutex
+	loggers map[string]*logger // containerID -> logger
+	streams map[string]*stream // log fifo path -> stream, for StopLogging
+}
+
+func newDriver() *driver {
+	return &driver{
+		loggers: make(map[string]*logger),
+		streams: make(map[string]*stream),
+	}
+}
+
+func (d *driver) register(containerID string, l *logger) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.loggers[containerID] = l
+}
+
+func (d *driver) unregister(containerID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.loggers, containerID)
+}
+
+// ServeMetrics writes the delivered/dropped/retried counters for every
+// container currently logging through this driver, one line per counter.
+func (d *driver) ServeMetrics(w http.ResponseWriter, _ *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for id, l := range d.loggers {
+		stats := l.Metrics()
+		fmt.Fprintf(w, "telegram_log_driver_delivered_total{container_id=%q} %d\n", id, stats.Delivered)
+		fmt.Fprintf(w, "telegram_log_driver_dropped_total{container_id=%q} %d\n", id, stats.Dropped)
+		fmt.Fprintf(w, "telegram_log_driver_retried_total{container_id=%q} %d\n", id, stats.Retried)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDriverServeMetrics(t *testing.T) {
+	t.Parallel()
+
+	cfg := defaultLoggerConfig
+	cfg.ClientConfig = defaultClientConfig
+	cfg.ClientConfig.ChatID = "chat_id"
+
+	client, err := NewClient(cfg.ClientConfig)
+	require.NoError(t, err)
+
+	l, err := newLogger(cfg, client)
+	require.NoError(t, err)
+	l.stats = LogStats{Delivered: 2, Dropped: 1, Retried: 3}
+
+	d := newDriver()
+	d.register("container1", l)
+
+	w := httptest.NewRecorder()
+	d.ServeMetrics(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	body := w.Body.String()
+	assert.Contains(t, body, `telegram_log_driver_delivered_total{container_id="container1"} 2`)
+	assert.Contains(t, body, `telegram_log_driver_dropped_total{container_id="container1"} 1`)
+	assert.Contains(t, body, `telegram_log_driver_retried_total{container_id="container1"} 3`)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// startLoggingRequest mirrors the body Docker's daemon sends to
+// /LogDriver.StartLogging: the path of the FIFO it will write the
+// container's log entries to, and the container's logging configuration.
+type startLoggingRequest struct {
+	File string
+	Info struct {
+		ContainerID string
+		Config      map[string]string
+	}
+}
+
+// stopLoggingRequest mirrors the body sent to /LogDriver.StopLogging.
+type stopLoggingRequest struct {
+	File string
+}
+
+// pluginResponse is the generic {Err: "..."} envelope every Docker plugin
+// protocol handler must reply with, empty on success.
+type pluginResponse struct {
+	Err string `json:"Err,omitempty"`
+}
+
+// handleStartLogging implements /LogDriver.StartLogging: it builds the
+// logger for the container described in the request body and starts
+// tailing its log FIFO.
+func (d *driver) handleStartLogging(w http.ResponseWriter, r *http.Request) {
+	var req startLoggingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writePluginResponse(w, fmt.Errorf("failed to decode request: %w", err))
+		return
+	}
+
+	cd := ContainerDetails{ContainerID: req.Info.ContainerID, Config: req.Info.Config}
+	writePluginResponse(w, d.startLogging(req.File, cd))
+}
+
+// handleStopLogging implements /LogDriver.StopLogging: it closes and
+// unregisters the logger associated with the FIFO named in the request.
+func (d *driver) handleStopLogging(w http.ResponseWriter, r *http.Request) {
+	var req stopLoggingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writePluginResponse(w, fmt.Errorf("failed to decode request: %w", err))
+		return
+	}
+
+	d.stopLogging(req.File)
+	writePluginResponse(w, nil)
+}
+
+// stream is the bookkeeping driver keeps for a FIFO that startLogging is
+// currently tailing, so that stopLogging can tear it down: closing file
+// unblocks consume's scanner.Scan() immediately, rather than leaving it
+// (and the logger it feeds) running after the container has stopped.
+type stream struct {
+	containerID string
+	file        *os.File
+}
+
+// startLogging builds a logger for cd, registers it for /metrics, and
+// starts a goroutine delivering every line written to file until
+// stopLogging is called for the same path.
+func (d *driver) startLogging(file string, cd ContainerDetails) error {
+	cfg, err := parseLoggerConfig(&cd)
+	if err != nil {
+		return fmt.Errorf("failed to parse logger config: %w", err)
+	}
+
+	client, err := NewClient(cfg.ClientConfig)
+	if err != nil {
+		return fmt.Errorf("failed to build client: %w", err)
+	}
+
+	l, err := newLogger(*cfg, client)
+	if err != nil {
+		return fmt.Errorf("failed to build logger: %w", err)
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("failed to open log fifo %q: %w", file, err)
+	}
+
+	d.register(cd.ContainerID, l)
+	d.mu.Lock()
+	d.streams[file] = &stream{containerID: cd.ContainerID, file: f}
+	d.mu.Unlock()
+
+	go l.run()
+	go d.consume(f, cd.ContainerID, l)
+
+	return nil
+}
+
+// stopLogging closes the FIFO and unregisters the logger that was started
+// for file, if any. Closing the FIFO unblocks consume's scanner.Scan(),
+// so no further lines are delivered once stopLogging returns. It is a
+// no-op if file isn't currently registered.
+func (d *driver) stopLogging(file string) {
+	d.mu.Lock()
+	s, ok := d.streams[file]
+	delete(d.streams, file)
+	d.mu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	d.unregister(s.containerID)
+	s.file.Close()
+}
+
+// consume reads newline-delimited log lines from f and hands each one to
+// l, until f is closed (by stopLogging, or by consume itself on return),
+// hits EOF, or fails to scan. The scanner's buffer is grown to
+// l.cfg.MaxBufferSize so a single line up to that size is never mistaken
+// for a scan failure.
+func (d *driver) consume(f *os.File, containerID string, l *logger) {
+	defer f.Close()
+	defer l.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), int(l.cfg.MaxBufferSize))
+	for scanner.Scan() {
+		_ = l.Log(LogMessage{
+			ContainerID: containerID,
+			Log:         scanner.Text(),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "telegram-log-driver: container %s: log scan failed: %v\n", containerID, err)
+	}
+}
+
+func writePluginResponse(w http.ResponseWriter, err error) {
+	resp := pluginResponse{}
+	if err != nil {
+		resp.Err = err.Error()
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
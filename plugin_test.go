@@ -0,0 +1,116 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDriverStartAndStopLogging(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1}}`))
+	}))
+	defer server.Close()
+
+	f, err := os.CreateTemp(t.TempDir(), "fifo")
+	require.NoError(t, err)
+	_, err = f.WriteString("hello\nworld\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	d := newDriver()
+	cd := ContainerDetails{
+		ContainerID: "container1",
+		Config: map[string]string{
+			cfgTokenKey:  "token",
+			cfgChatIDKey: "chat_id",
+			cfgURLKey:    server.URL,
+		},
+	}
+
+	require.NoError(t, d.startLogging(f.Name(), cd))
+
+	require.Eventually(t, func() bool {
+		d.mu.Lock()
+		l, ok := d.loggers["container1"]
+		d.mu.Unlock()
+		return ok && l.Metrics().Delivered == 2
+	}, time.Second, 10*time.Millisecond)
+
+	d.stopLogging(f.Name())
+
+	d.mu.Lock()
+	_, ok := d.loggers["container1"]
+	d.mu.Unlock()
+	assert.False(t, ok)
+}
+
+func TestDriverStopLoggingStopsDelivery(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var calls int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1}}`))
+	}))
+	defer server.Close()
+
+	fifoPath := filepath.Join(t.TempDir(), "fifo")
+	require.NoError(t, syscall.Mkfifo(fifoPath, 0o600))
+
+	writerReady := make(chan *os.File, 1)
+	go func() {
+		w, err := os.OpenFile(fifoPath, os.O_WRONLY, 0)
+		require.NoError(t, err)
+		writerReady <- w
+	}()
+
+	d := newDriver()
+	cd := ContainerDetails{
+		ContainerID: "container1",
+		Config: map[string]string{
+			cfgTokenKey:  "token",
+			cfgChatIDKey: "chat_id",
+			cfgURLKey:    server.URL,
+		},
+	}
+	require.NoError(t, d.startLogging(fifoPath, cd))
+
+	writer := <-writerReady
+	defer writer.Close()
+
+	_, err := writer.WriteString("one\ntwo\nthree\n")
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls == 3
+	}, time.Second, 10*time.Millisecond)
+
+	d.stopLogging(fifoPath)
+
+	// The FIFO's read end is now closed, so writing into it must fail
+	// rather than reach the (now-unregistered) logger.
+	time.Sleep(50 * time.Millisecond)
+	_, err = writer.WriteString("four\n")
+	assert.Error(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 3, calls)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"strings"
+	"text/template"
+)
+
+// Renderer turns a LogMessage into the text to send and the Telegram
+// parse_mode (if any) it should be sent with, according to the
+// container's configured "format" option.
+type Renderer interface {
+	Render(msg LogMessage) (text, parseMode string, err error)
+}
+
+// newRenderer builds the Renderer for format, applying tmpl to the
+// formats that still run the message through the user's template before
+// escaping it.
+func newRenderer(format string, tmpl *template.Template) (Renderer, error) {
+	switch format {
+	case formatText:
+		return textRenderer{tmpl: tmpl}, nil
+	case formatJSON:
+		return jsonRenderer{}, nil
+	case formatLogfmt:
+		return logfmtRenderer{}, nil
+	case formatMarkdownV2:
+		return markdownV2Renderer{tmpl: tmpl}, nil
+	case formatHTML:
+		return htmlRenderer{tmpl: tmpl}, nil
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// textRenderer is the original, parse_mode-less template path.
+type textRenderer struct {
+	tmpl *template.Template
+}
+
+func (r textRenderer) Render(msg LogMessage) (string, string, error) {
+	text, err := executeTemplate(r.tmpl, msg)
+	return text, "", err
+}
+
+// jsonRenderer passes a container's already-structured JSON log lines
+// straight through, untouched by the template.
+type jsonRenderer struct{}
+
+func (jsonRenderer) Render(msg LogMessage) (string, string, error) {
+	return msg.Log, "", nil
+}
+
+// logfmtRenderer renders the message as logfmt key=value pairs.
+type logfmtRenderer struct{}
+
+func (logfmtRenderer) Render(msg LogMessage) (string, string, error) {
+	return fmt.Sprintf("container=%q log=%q", msg.ContainerName, msg.Log), "", nil
+}
+
+// markdownV2Renderer escapes the template output per Telegram's MarkdownV2
+// rules and sends it with parse_mode MarkdownV2.
+type markdownV2Renderer struct {
+	tmpl *template.Template
+}
+
+func (r markdownV2Renderer) Render(msg LogMessage) (string, string, error) {
+	text, err := executeTemplate(r.tmpl, msg)
+	if err != nil {
+		return "", "", err
+	}
+
+	return escapeMarkdownV2(text), "MarkdownV2", nil
+}
+
+// markdownV2SpecialChars are the characters Telegram's MarkdownV2 parser
+// requires to be escaped outside of an entity.
+const markdownV2SpecialChars = "_*[]()~`>#+-=|{}.!\\"
+
+func escapeMarkdownV2(s string) string {
+	var buf strings.Builder
+	buf.Grow(len(s))
+
+	for _, r := range s {
+		if strings.ContainsRune(markdownV2SpecialChars, r) {
+			buf.WriteByte('\\')
+		}
+		buf.WriteRune(r)
+	}
+
+	return buf.String()
+}
+
+// htmlRenderer escapes the template output as HTML and sends it with
+// parse_mode HTML.
+type htmlRenderer struct {
+	tmpl *template.Template
+}
+
+func (r htmlRenderer) Render(msg LogMessage) (string, string, error) {
+	text, err := executeTemplate(r.tmpl, msg)
+	if err != nil {
+		return "", "", err
+	}
+
+	return html.EscapeString(text), "HTML", nil
+}
+
+func executeTemplate(tmpl *template.Template, msg LogMessage) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, msg); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
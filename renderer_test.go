@@ -0,0 +1,96 @@
+package main
+
+import (
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRenderer(t *testing.T) {
+	t.Parallel()
+
+	tmpl, err := template.New("message").Parse("{{.Log}}")
+	require.NoError(t, err)
+
+	msg := LogMessage{ContainerName: "web", Log: "hello *world*"}
+
+	tests := []struct {
+		name          string
+		format        string
+		wantText      string
+		wantParseMode string
+	}{
+		{
+			name:          "text",
+			format:        formatText,
+			wantText:      "hello *world*",
+			wantParseMode: "",
+		},
+		{
+			name:          "json",
+			format:        formatJSON,
+			wantText:      "hello *world*",
+			wantParseMode: "",
+		},
+		{
+			name:          "logfmt",
+			format:        formatLogfmt,
+			wantText:      `container="web" log="hello *world*"`,
+			wantParseMode: "",
+		},
+		{
+			name:          "markdown-v2",
+			format:        formatMarkdownV2,
+			wantText:      `hello \*world\*`,
+			wantParseMode: "MarkdownV2",
+		},
+		{
+			name:          "html",
+			format:        formatHTML,
+			wantText:      "hello *world*",
+			wantParseMode: "HTML",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := newRenderer(tt.format, tmpl)
+			require.NoError(t, err)
+
+			text, parseMode, err := r.Render(msg)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantText, text)
+			assert.Equal(t, tt.wantParseMode, parseMode)
+		})
+	}
+}
+
+func TestNewRendererInvalidFormat(t *testing.T) {
+	t.Parallel()
+
+	_, err := newRenderer("yaml", nil)
+	require.Error(t, err)
+}
+
+func TestEscapeMarkdownV2(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, `hello \*world\*`, escapeMarkdownV2("hello *world*"))
+	assert.Equal(t, `a\.b\!c`, escapeMarkdownV2("a.b!c"))
+	assert.Equal(t, "plain text", escapeMarkdownV2("plain text"))
+}
+
+func TestHTMLRendererEscapes(t *testing.T) {
+	t.Parallel()
+
+	tmpl, err := template.New("message").Parse("{{.Log}}")
+	require.NoError(t, err)
+
+	r := htmlRenderer{tmpl: tmpl}
+	text, parseMode, err := r.Render(LogMessage{Log: "<b>bold</b> & stuff"})
+	require.NoError(t, err)
+	assert.Equal(t, "&lt;b&gt;bold&lt;/b&gt; &amp; stuff", text)
+	assert.Equal(t, "HTML", parseMode)
+}